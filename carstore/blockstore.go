@@ -0,0 +1,90 @@
+package carstore
+
+import (
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/exp/mmap"
+)
+
+// Blockstore is a read-only overlay that resolves Get(cid) by consulting a
+// Registry for the shard that contains it, then mmap'ing that shard's
+// CARv2 file and reading the block directly out of it at the indexed
+// offset -- no block bytes are ever copied into the datastore.
+type Blockstore struct {
+	registry *Registry
+}
+
+// NewBlockstore creates a shard-backed, read-only blockstore overlay. It is
+// meant to be composed with the base datastore blockstore in Storage(), not
+// used standalone.
+func NewBlockstore(registry *Registry) *Blockstore {
+	return &Blockstore{registry: registry}
+}
+
+// Has reports whether c is resolvable through some registered shard.
+func (b *Blockstore) Has(c cid.Cid) (bool, error) {
+	_, ok := b.registry.ShardFor(c)
+	return ok, nil
+}
+
+// Get resolves c to its shard via the registry and reads the block
+// directly out of that shard's CARv2 file at the indexed offset.
+func (b *Blockstore) Get(c cid.Cid) (blocks.Block, error) {
+	shard, ok := b.registry.ShardFor(c)
+	if !ok {
+		return nil, fmt.Errorf("carstore: no shard registered for %s", c)
+	}
+
+	offset, ok := shard.Index.Offset(c)
+	if !ok {
+		return nil, fmt.Errorf("carstore: shard %s has no index entry for %s", shard.Path, c)
+	}
+
+	r, err := mmap.Open(shard.Path)
+	if err != nil {
+		return nil, fmt.Errorf("carstore: opening shard %s: %w", shard.Path, err)
+	}
+	defer r.Close()
+
+	return readFrameAt(r, offset, c)
+}
+
+// Roots returns the root CID of every registered shard, re-checking each
+// against the registry so a shard unregistered concurrently with this call
+// is dropped from the result rather than handed back stale.
+func (b *Blockstore) Roots() []cid.Cid {
+	roots := b.registry.Roots()
+	out := make([]cid.Cid, 0, len(roots))
+	for _, root := range roots {
+		if b.registry.IsPinned(root) {
+			out = append(out, root)
+		}
+	}
+	return out
+}
+
+// readFrameAt reads a single CAR frame (varint length prefix, then a CID,
+// then the raw block bytes) at offset out of an mmap'd CAR file.
+func readFrameAt(r *mmap.ReaderAt, offset uint64, want cid.Cid) (blocks.Block, error) {
+	frameLen, viLen, err := readUvarintAt(r, int64(offset))
+	if err != nil {
+		return nil, fmt.Errorf("carstore: reading frame length at %d: %w", offset, err)
+	}
+
+	frame := make([]byte, frameLen)
+	if _, err := r.ReadAt(frame, int64(offset)+int64(viLen)); err != nil {
+		return nil, err
+	}
+
+	cidLen, gotCid, err := cid.CidFromBytes(frame)
+	if err != nil {
+		return nil, err
+	}
+	if !gotCid.Equals(want) {
+		return nil, fmt.Errorf("carstore: index offset %d points at %s, wanted %s", offset, gotCid, want)
+	}
+
+	return blocks.NewBlockWithCid(frame[cidLen:], want)
+}
@@ -0,0 +1,102 @@
+package carstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// carV2PragmaSize is the length, in bytes, of a CARv2 file's fixed pragma: a
+// varint-prefixed DAG-CBOR array tagging the file as CARv2, always encoded
+// the same way and always this many bytes.
+const carV2PragmaSize = 11
+
+// carV2HeaderSize is the length, in bytes, of the fixed CARv2 header that
+// immediately follows the pragma: 16 bytes of characteristics bits, then
+// three little-endian uint64 offsets (data offset, data size, index
+// offset), in that order.
+const carV2HeaderSize = 16 + 8 + 8 + 8
+
+// mapIndex is an in-memory Index built by scanning a CAR payload once at
+// import time; it is what ImportCAR registers a shard with.
+type mapIndex map[string]uint64
+
+func (m mapIndex) Offset(c cid.Cid) (uint64, bool) {
+	off, ok := m[c.KeyString()]
+	return off, ok
+}
+
+// ImportCAR registers path as a shard rooted at root, building its index by
+// scanning the CARv1 payload starting at payloadOffset (for a CARv2 file,
+// this is the data payload offset recorded in its header; for a bare CARv1
+// file it is 0). The CAR's blocks are never copied out of path; only their
+// offsets are kept, in the index of the Shard it registers.
+//
+// Most callers have a path, not an already-parsed payload offset/size; use
+// ImportCARFile for those.
+func ImportCAR(registry *Registry, root cid.Cid, path string, payload io.ReaderAt, payloadOffset, payloadSize uint64) error {
+	idx := make(mapIndex)
+
+	var pos uint64
+	for pos < payloadSize {
+		frameLen, viLen, err := readUvarintAt(payload, int64(payloadOffset+pos))
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("carstore: reading frame length at %d: %w", pos, err)
+		}
+
+		frame := make([]byte, frameLen)
+		if _, err := payload.ReadAt(frame, int64(payloadOffset+pos+uint64(viLen))); err != nil {
+			return fmt.Errorf("carstore: reading frame at %d: %w", pos, err)
+		}
+
+		cidLen, c, err := cid.CidFromBytes(frame)
+		if err != nil {
+			return fmt.Errorf("carstore: decoding cid at offset %d: %w", pos, err)
+		}
+		_ = cidLen
+
+		// Store an absolute file offset, not one relative to
+		// payloadOffset: Blockstore.Get mmaps the whole shard file and
+		// reads directly at the indexed offset.
+		idx[c.KeyString()] = payloadOffset + pos
+		pos += uint64(viLen) + frameLen
+	}
+
+	return registry.Register(&Shard{
+		Root:  root,
+		Path:  path,
+		Index: idx,
+	})
+}
+
+// ImportCARFile is the "Files"/import path for `.car` files: given a path to
+// a CARv2 file and the root it should be registered under, it parses the
+// file's own CARv2 pragma and header to find the CARv1 payload's offset and
+// size, then registers it as a shard via ImportCAR, rather than unpacking
+// every block into the datastore. This is the function an `ipfs add --car`
+// style command would call; no such command exists in this tree yet, so
+// ImportCARFile currently has no caller of its own beyond tests.
+func ImportCARFile(registry *Registry, root cid.Cid, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("carstore: opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, carV2PragmaSize+carV2HeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return fmt.Errorf("carstore: reading CARv2 header of %s: %w", path, err)
+	}
+
+	characteristics := carV2PragmaSize
+	dataOffset := binary.LittleEndian.Uint64(header[characteristics+16 : characteristics+24])
+	dataSize := binary.LittleEndian.Uint64(header[characteristics+24 : characteristics+32])
+
+	return ImportCAR(registry, root, path, f, dataOffset, dataSize)
+}
@@ -0,0 +1,99 @@
+package carstore
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	util "github.com/ipfs/go-ipfs-util"
+)
+
+// TestImportAndReadRoundTrip simulates a CARv2 file with a nonzero payload
+// offset (a header before the CARv1 payload) and checks that a block
+// imported via ImportCAR reads back correctly through Blockstore -- this is
+// the offset arithmetic ImportCAR and Blockstore.Get have to agree on.
+func TestImportAndReadRoundTrip(t *testing.T) {
+	data := []byte("hello carstore")
+	c := cid.NewCidV1(cid.Raw, util.Hash(data))
+
+	frame := append(append([]byte{}, c.Bytes()...), data...)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(lenBuf, uint64(len(frame)))
+
+	header := []byte("fake-carv2-header-pragma")
+	content := append(append([]byte{}, header...), lenBuf[:n]...)
+	content = append(content, frame...)
+
+	path := filepath.Join(t.TempDir(), "shard.car")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	registry := NewRegistry()
+	payloadOffset := uint64(len(header))
+	payloadSize := uint64(n + len(frame))
+	if err := ImportCAR(registry, c, path, f, payloadOffset, payloadSize); err != nil {
+		t.Fatalf("ImportCAR: %v", err)
+	}
+
+	bs := NewBlockstore(registry)
+	if ok, err := bs.Has(c); err != nil || !ok {
+		t.Fatalf("Has(%s) = %v, %v; want true, nil", c, ok, err)
+	}
+
+	got, err := bs.Get(c)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got.RawData()) != string(data) {
+		t.Fatalf("Get returned %q, want %q", got.RawData(), data)
+	}
+}
+
+// TestImportCARFileParsesV2Header builds a real CARv2 pragma/header (rather
+// than TestImportAndReadRoundTrip's opaque fake one) and checks that ImportCARFile
+// derives the right payload offset/size from it instead of being told them.
+func TestImportCARFileParsesV2Header(t *testing.T) {
+	data := []byte("hello carstore v2")
+	c := cid.NewCidV1(cid.Raw, util.Hash(data))
+
+	frame := append(append([]byte{}, c.Bytes()...), data...)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(lenBuf, uint64(len(frame)))
+	payload := append(lenBuf[:n], frame...)
+
+	pragma := make([]byte, carV2PragmaSize)
+	header := make([]byte, carV2HeaderSize)
+	dataOffset := uint64(len(pragma) + len(header))
+	binary.LittleEndian.PutUint64(header[16:24], dataOffset)
+	binary.LittleEndian.PutUint64(header[24:32], uint64(len(payload)))
+
+	content := append(append(pragma, header...), payload...)
+
+	path := filepath.Join(t.TempDir(), "shard.car")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	registry := NewRegistry()
+	if err := ImportCARFile(registry, c, path); err != nil {
+		t.Fatalf("ImportCARFile: %v", err)
+	}
+
+	bs := NewBlockstore(registry)
+	got, err := bs.Get(c)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got.RawData()) != string(data) {
+		t.Fatalf("Get returned %q, want %q", got.RawData(), data)
+	}
+}
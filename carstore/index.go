@@ -0,0 +1,18 @@
+package carstore
+
+import (
+	cid "github.com/ipfs/go-cid"
+)
+
+// Index maps a block's CID to its byte offset within a CARv2 file.
+// The only implementation today, mapIndex, is keyed by the full CID, so a
+// CID of another version or codec over the same multihash is not found. It
+// is an in-memory map rebuilt by a
+// full linear scan on every ImportCAR call -- there is no on-disk index
+// format yet, so nothing is persisted across process restarts and every
+// shard is re-scanned at import time even if it was previously imported.
+type Index interface {
+	// Offset returns the byte offset of c's block within the shard's CARv2
+	// file, and whether the index has an entry for it.
+	Offset(c cid.Cid) (uint64, bool)
+}
@@ -0,0 +1,90 @@
+// Package carstore implements the experimental CARv2-backed shard
+// blockstore: a set of CARv2 files, each paired with an in-memory
+// CID-to-offset index built at import time (no index is written to disk),
+// that blocks can be resolved from without unpacking them into the regular
+// datastore.
+package carstore
+
+import (
+	"fmt"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// Shard describes one imported CAR file: its path on disk and the index
+// used to resolve a block's offset within it.
+type Shard struct {
+	Root  cid.Cid
+	Path  string
+	Index Index
+}
+
+// Registry tracks the shards currently registered with the node, keyed by
+// root CID. IsPinned reports whether a root is registered; in this package
+// its only caller is Blockstore.Roots, and the pinner and garbage collector
+// do not call it.
+type Registry struct {
+	mu     sync.RWMutex
+	shards map[string]*Shard // keyed by Root.KeyString()
+}
+
+// NewRegistry creates an empty shard registry.
+func NewRegistry() *Registry {
+	return &Registry{shards: make(map[string]*Shard)}
+}
+
+// Register adds a shard to the registry, making its root considered pinned
+// and its blocks resolvable via the shard blockstore.
+func (r *Registry) Register(s *Shard) error {
+	if !s.Root.Defined() {
+		return fmt.Errorf("carstore: shard %s has no root CID", s.Path)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shards[s.Root.KeyString()] = s
+	return nil
+}
+
+// Unregister removes a shard's root from the registry. It does not touch
+// the underlying CAR file.
+func (r *Registry) Unregister(root cid.Cid) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.shards, root.KeyString())
+}
+
+// ShardFor returns the shard that contains the block addressed by c, if
+// any shard's index knows about it.
+func (r *Registry) ShardFor(c cid.Cid) (*Shard, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.shards {
+		if _, ok := s.Index.Offset(c); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// IsPinned reports whether root is registered as a shard root.
+func (r *Registry) IsPinned(root cid.Cid) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.shards[root.KeyString()]
+	return ok
+}
+
+// Roots returns the root CID of every registered shard.
+func (r *Registry) Roots() []cid.Cid {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]cid.Cid, 0, len(r.shards))
+	for _, s := range r.shards {
+		out = append(out, s.Root)
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package carstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// readUvarintAt reads a binary.Uvarint one byte at a time out of r starting
+// at off, returning the decoded value and the number of bytes it occupied.
+// io.ReaderAt (including golang.org/x/exp/mmap.ReaderAt, which both
+// Blockstore and ImportCAR read shards through) is required to return a
+// non-nil error whenever it returns fewer bytes than requested, including at
+// EOF -- so a single fixed-size read for the up-to-10-byte varint prefix
+// fails spuriously whenever a frame's remaining bytes-to-EOF are under 10,
+// even though the varint itself is fully present. Reading one byte at a
+// time only ever asks for bytes that are actually there.
+func readUvarintAt(r io.ReaderAt, off int64) (value uint64, n int, err error) {
+	var buf [binary.MaxVarintLen64]byte
+	for n = 0; n < len(buf); n++ {
+		if _, err := r.ReadAt(buf[n:n+1], off+int64(n)); err != nil {
+			return 0, 0, err
+		}
+		if buf[n] < 0x80 {
+			value, viLen := binary.Uvarint(buf[:n+1])
+			if viLen <= 0 {
+				return 0, 0, fmt.Errorf("carstore: invalid varint at offset %d", off)
+			}
+			return value, viLen, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("carstore: varint at offset %d longer than %d bytes", off, len(buf))
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/ipfs/go-ipfs/core/node"
+	"github.com/ipfs/go-ipfs/provider"
+	"github.com/ipfs/go-ipfs/repo"
+)
+
+// IpfsNode is the handle onto a node assembled from the fx dependency graph
+// built by node.IPFS(). Fields are populated by fx.Extract in NewNode, so
+// adding one here is enough to make it reachable from the rest of the
+// codebase -- no change to node.IPFS() or its constructors is needed.
+type IpfsNode struct {
+	ctx context.Context
+	app *fx.App
+
+	Repo     repo.Repo
+	IsOnline bool
+
+	// Provider announces the node's blocks to the network and reprovides
+	// them on a schedule. The `ipfs bitswap reprovide` command calls
+	// Reprovide below rather than depending on a concrete provider.System
+	// implementation. node.Override does not replace the default
+	// provider.System (see node.Providers).
+	Provider provider.System
+}
+
+// BuildCfg is the configuration NewNode builds an IpfsNode from. See
+// node.BuildSettings / node.Option for the alternate, composable
+// construction path used by NewNodeWithOptions.
+type BuildCfg = node.BuildCfg
+
+// NewNode builds and starts an IpfsNode from a *BuildCfg, e.g.:
+//
+//	n, err := core.NewNode(ctx, &core.BuildCfg{Online: true, Repo: r})
+func NewNode(ctx context.Context, cfg *BuildCfg) (*IpfsNode, error) {
+	n := &IpfsNode{ctx: ctx}
+
+	app := fx.New(
+		node.IPFS(ctx, cfg),
+		fx.NopLogger,
+		fx.Extract(n),
+	)
+	n.app = app
+
+	if app.Err() != nil {
+		return nil, app.Err()
+	}
+
+	if err := app.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// Close tears down the node's dependency graph.
+func (n *IpfsNode) Close() error {
+	return n.app.Stop(n.ctx)
+}
+
+// Reprovide immediately reprovides the node's content via its configured
+// provider.System.
+func (n *IpfsNode) Reprovide(ctx context.Context) error {
+	return n.Provider.Reprovide(ctx)
+}
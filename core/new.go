@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+
+	"github.com/ipfs/go-ipfs/core/node"
+
+	"go.uber.org/fx"
+)
+
+// NewNodeWithOptions builds and starts an IpfsNode from node.Option values
+// rather than a *BuildCfg, e.g.:
+//
+//	n, err := core.NewNodeWithOptions(ctx, node.Repo(r), node.Online())
+//
+// It populates and returns the same *IpfsNode NewNode does, so embedders
+// keep full access to the node while extending or overriding its
+// dependency graph via node.Provide / node.Invoke / node.Override, without
+// forking IPFS().
+func NewNodeWithOptions(ctx context.Context, opts ...node.Option) (*IpfsNode, error) {
+	settings, err := node.BuildSettings(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	bcfg := &BuildCfg{
+		Online: settings.Online,
+		Repo:   settings.Repo,
+	}
+
+	n := &IpfsNode{ctx: settings.Ctx}
+
+	app := fx.New(
+		settings.Options(bcfg),
+		fx.NopLogger,
+		fx.Extract(n),
+	)
+	n.app = app
+
+	if app.Err() != nil {
+		return nil, app.Err()
+	}
+
+	if err := app.Start(settings.Ctx); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,233 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/ipfs/go-ipfs-config"
+	"github.com/ipfs/go-ipfs/repo"
+	"go.uber.org/fx"
+)
+
+// Settings accumulates the pieces a node.Option can set: the defaults
+// assembled by IPFS() plus whatever the caller adds or replaces on top.
+type Settings struct {
+	Ctx    context.Context
+	Repo   repo.Repo
+	Cfg    *config.Config
+	Online bool
+
+	extra     []fx.Option
+	overrides map[reflect.Type]interface{}
+}
+
+// Option is a user-facing node construction option, wrapping an fx.Option
+// plus enough metadata (via Settings) for Override to replace a default
+// constructor already registered in the graph IPFS() builds.
+type Option func(*Settings) error
+
+// Provide registers additional fx constructors alongside the default graph,
+// e.g. core.NewNodeWithOptions(ctx, node.Provide(myExtraService)).
+func Provide(ctors ...interface{}) Option {
+	return func(s *Settings) error {
+		for _, ctor := range ctors {
+			s.extra = append(s.extra, fx.Provide(ctor))
+		}
+		return nil
+	}
+}
+
+// Invoke registers additional fx invocations alongside the default graph,
+// e.g. core.NewNodeWithOptions(ctx, node.Invoke(myStartupHook)).
+func Invoke(fns ...interface{}) Option {
+	return func(s *Settings) error {
+		for _, fn := range fns {
+			s.extra = append(s.extra, fx.Invoke(fn))
+		}
+		return nil
+	}
+}
+
+// Override replaces a constructor already registered in the default graph
+// with ctor. target is any function with the same return type as the
+// constructor being replaced, matched by its return type alone -- the
+// function itself is never called. For a plain constructor, pass the
+// constructor itself, e.g. `node.Override(libp2p.Host, myHostCtor)`.
+//
+// Some defaults, like BaseBlockstoreCtor, are curried factories: Storage()
+// calls BaseBlockstoreCtor(cacheOpts, ...) to get the actual fx constructor,
+// and it's that returned constructor's return type which is registered in
+// the graph. To override one of these, invoke the factory the same way
+// Storage() does and pass its result as target, e.g.
+// `node.Override(node.BaseBlockstoreCtor(cacheOpts, false, false), myCtor)`
+// -- passing the factory itself would match on the factory's own return
+// type, not the blockstore's, and never take effect.
+//
+// dig does not allow two providers for the same type, so the default
+// constructor for that type is dropped from the graph entirely -- it is
+// never called, not merely shadowed -- whenever a matching override is
+// present; see provideOrOverride.
+func Override(target, ctor interface{}) Option {
+	return func(s *Settings) error {
+		t := reflect.TypeOf(target)
+		if t == nil || t.Kind() != reflect.Func || t.NumOut() == 0 {
+			return errors.New("node.Override: target must be a constructor function")
+		}
+
+		if s.overrides == nil {
+			s.overrides = make(map[reflect.Type]interface{})
+		}
+		s.overrides[t.Out(0)] = ctor
+		return nil
+	}
+}
+
+// Online marks the node as networked. Any options passed in are applied on
+// top of the online graph, e.g. node.Online(node.Override(libp2p.Host, myHostCtor)).
+func Online(opts ...Option) Option {
+	return func(s *Settings) error {
+		s.Online = true
+		return applyOptions(s, opts)
+	}
+}
+
+// Offline marks the node as offline. Any options passed in are applied on
+// top of the offline graph.
+func Offline(opts ...Option) Option {
+	return func(s *Settings) error {
+		s.Online = false
+		return applyOptions(s, opts)
+	}
+}
+
+// Repo sets the repo.Repo the node is built from. Its config is used unless
+// overridden by Config.
+func Repo(r repo.Repo) Option {
+	return func(s *Settings) error {
+		s.Repo = r
+		return nil
+	}
+}
+
+// Ctx sets the base context the node and its background processes run
+// under.
+func Ctx(ctx context.Context) Option {
+	return func(s *Settings) error {
+		s.Ctx = ctx
+		return nil
+	}
+}
+
+// Config overrides the config read from Repo. It works by registering an
+// override for *config.Config's provider the same way Override does, so
+// Storage's provideOrOverride(ctx, repo.Repo.Config) picks it up instead of
+// reading the repo's on-disk config, and IPFS() substitutes it (via
+// configOverride) for the cfg every cfg-driven branch -- Routing.Type,
+// Reprovider.Strategy, Pubsub.Router, Experimental.CarStoreEnabled, and so
+// on -- decides on, in place of whatever bcfg.options(ctx) resolved from the
+// repo.
+func Config(cfg *config.Config) Option {
+	return func(s *Settings) error {
+		s.Cfg = cfg
+
+		if s.overrides == nil {
+			s.overrides = make(map[reflect.Type]interface{})
+		}
+		s.overrides[reflect.TypeOf(cfg)] = func() *config.Config { return cfg }
+		return nil
+	}
+}
+
+func applyOptions(s *Settings, opts []Option) error {
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildSettings applies opts in order and returns the resulting Settings.
+func BuildSettings(ctx context.Context, opts ...Option) (*Settings, error) {
+	s := &Settings{Ctx: ctx}
+	if err := applyOptions(s, opts); err != nil {
+		return nil, err
+	}
+	if s.Ctx == nil {
+		s.Ctx = ctx
+	}
+	return s, nil
+}
+
+// Options returns the fx.Option graph for these settings: the default IPFS()
+// graph built from bcfg (with overridden constructors dropped per
+// provideOrOverride), plus whatever node.Provide/node.Invoke added on top.
+func (s *Settings) Options(bcfg *BuildCfg) fx.Option {
+	ctx := withOverrides(s.Ctx, s.overrides)
+	return fx.Options(
+		IPFS(ctx, bcfg),
+		fx.Options(s.extra...),
+	)
+}
+
+type overridesKey struct{}
+
+func withOverrides(ctx context.Context, overrides map[reflect.Type]interface{}) context.Context {
+	if len(overrides) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, overridesKey{}, overrides)
+}
+
+// configOverride returns the *config.Config registered via node.Config, if
+// any, and whether one was found. Unlike provideOrOverride, which only
+// affects what provideOrOverride(ctx, repo.Repo.Config) feeds into the fx
+// graph, this lets IPFS() substitute the override for the cfg value its own
+// imperative branching closes over -- bcfg.options(ctx) resolves cfg before
+// any of that branching runs, so without this, node.Config changed what
+// *config.Config other constructors received via DI but never touched
+// Routing.Type/Reprovider.Strategy/etc. decisions made directly in IPFS()'s
+// body.
+func configOverride(ctx context.Context) (*config.Config, bool) {
+	overrides, _ := ctx.Value(overridesKey{}).(map[reflect.Type]interface{})
+	if len(overrides) == 0 {
+		return nil, false
+	}
+
+	ctor, ok := overrides[reflect.TypeOf((*config.Config)(nil))]
+	if !ok {
+		return nil, false
+	}
+
+	fn, ok := ctor.(func() *config.Config)
+	if !ok {
+		return nil, false
+	}
+	return fn(), true
+}
+
+// provideOrOverride returns fx.Provide(def), unless ctx carries a
+// node.Override for def's return type, in which case the override
+// constructor is provided instead and def is never called nor provided --
+// this is what lets node.Override swap a default constructor (e.g.
+// OnlineExchange, libp2p.Host, or an already-invoked BaseBlockstoreCtor)
+// for one supplied by an embedder.
+func provideOrOverride(ctx context.Context, def interface{}) fx.Option {
+	overrides, _ := ctx.Value(overridesKey{}).(map[reflect.Type]interface{})
+	if len(overrides) == 0 {
+		return fx.Provide(def)
+	}
+
+	t := reflect.TypeOf(def)
+	if t.Kind() == reflect.Func && t.NumOut() > 0 {
+		if ctor, ok := overrides[t.Out(0)]; ok {
+			return fx.Provide(ctor)
+		}
+	}
+
+	return fx.Provide(def)
+}
@@ -0,0 +1,54 @@
+package node
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestOverrideKeysByReturnType(t *testing.T) {
+	type widget struct{}
+	defCtor := func() *widget { return &widget{} }
+	myCtor := func() *widget { return &widget{} }
+
+	s, err := BuildSettings(context.Background(), Override(defCtor, myCtor))
+	if err != nil {
+		t.Fatalf("BuildSettings: %v", err)
+	}
+
+	want := reflect.TypeOf(defCtor).Out(0)
+	got, ok := s.overrides[want]
+	if !ok {
+		t.Fatalf("Override did not register an entry for %s", want)
+	}
+	if reflect.ValueOf(got).Pointer() != reflect.ValueOf(myCtor).Pointer() {
+		t.Fatalf("registered override is not myCtor")
+	}
+}
+
+func TestOverrideOfCurriedFactoryMustUseInvokedForm(t *testing.T) {
+	// A curried factory like BaseBlockstoreCtor returns a constructor, not
+	// the final value: Storage() calls provideOrOverride on the
+	// *invoked* constructor (return type int here), not on the factory
+	// (return type func() int). Overriding the factory itself registers
+	// the wrong key and provideOrOverride will never find it.
+	factory := func(n int) func() int {
+		return func() int { return n }
+	}
+	invoked := factory(1)
+
+	s, err := BuildSettings(context.Background(), Override(factory, func() int { return 2 }))
+	if err != nil {
+		t.Fatalf("BuildSettings: %v", err)
+	}
+
+	invokedType := reflect.TypeOf(invoked).Out(0) // int
+	if _, ok := s.overrides[invokedType]; ok {
+		t.Fatalf("override of the factory must not be keyed under the invoked constructor's return type")
+	}
+
+	factoryType := reflect.TypeOf(factory).Out(0) // func() int
+	if _, ok := s.overrides[factoryType]; !ok {
+		t.Fatalf("expected Override(factory, ...) to key on the factory's own return type")
+	}
+}
@@ -9,15 +9,17 @@ import (
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipfs/go-ipfs-config"
 	util "github.com/ipfs/go-ipfs-util"
+	metrics "github.com/ipfs/go-metrics-interface"
 	peer "github.com/libp2p/go-libp2p-peer"
 	"github.com/libp2p/go-libp2p-peerstore/pstoremem"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 
+	"github.com/ipfs/go-ipfs/carstore"
 	"github.com/ipfs/go-ipfs/core/node/libp2p"
 	"github.com/ipfs/go-ipfs/p2p"
 	"github.com/ipfs/go-ipfs/provider"
+	"github.com/ipfs/go-ipfs/provider/simple"
 	"github.com/ipfs/go-ipfs/repo"
-	"github.com/ipfs/go-ipfs/reprovide"
 
 	offline "github.com/ipfs/go-ipfs-exchange-offline"
 	offroute "github.com/ipfs/go-ipfs-routing/offline"
@@ -26,19 +28,23 @@ import (
 	"go.uber.org/fx"
 )
 
-var BaseLibP2P = fx.Options(
-	fx.Provide(libp2p.PNet),
-	fx.Provide(libp2p.ConnectionManager),
-	fx.Provide(libp2p.DefaultTransports),
+// BaseLibP2P groups basic libp2p units. Host is overridable via
+// node.Override so embedders can swap in their own host constructor.
+func BaseLibP2P(ctx context.Context) fx.Option {
+	return fx.Options(
+		fx.Provide(libp2p.PNet),
+		fx.Provide(libp2p.ConnectionManager),
+		fx.Provide(libp2p.DefaultTransports),
 
-	fx.Provide(libp2p.Host),
+		provideOrOverride(ctx, libp2p.Host),
 
-	fx.Provide(libp2p.DiscoveryHandler),
+		fx.Provide(libp2p.DiscoveryHandler),
 
-	fx.Invoke(libp2p.PNetChecker),
-)
+		fx.Invoke(libp2p.PNetChecker),
+	)
+}
 
-func LibP2P(bcfg *BuildCfg, cfg *config.Config) fx.Option {
+func LibP2P(ctx context.Context, bcfg *BuildCfg, cfg *config.Config) fx.Option {
 	// parse ConnMgr config
 
 	grace := config.DefaultConnMgrGracePeriod
@@ -87,16 +93,39 @@ func LibP2P(bcfg *BuildCfg, cfg *config.Config) fx.Option {
 		case "floodsub":
 			ps = fx.Provide(libp2p.FloodSub(pubsubOptions...))
 		case "gossipsub":
+			gsOpts, err := gossipSubOptions(cfg)
+			if err != nil {
+				return fx.Error(err)
+			}
+			pubsubOptions = append(pubsubOptions, gsOpts...)
+
 			ps = fx.Provide(libp2p.GossipSub(pubsubOptions...))
 		default:
 			return fx.Error(fmt.Errorf("unknown pubsub router %s", cfg.Pubsub.Router))
 		}
 	}
 
+	// parse Routing config
+
+	routing := fx.Options(
+		fx.Provide(libp2p.Routing),
+		fx.Provide(libp2p.BaseRouting),
+	)
+
+	switch cfg.Routing.Type {
+	case "", "dht", "dhtclient", "none":
+		// handled by libp2p.Routing/BaseRouting above, driven by
+		// bcfg.getOpt("dht")/("dhtclient") as before
+	case "custom":
+		routing = libp2p.CustomRouting(cfg.Routing.Routers, cfg.Routing.Methods)
+	default:
+		return fx.Error(fmt.Errorf("unrecognized Routing.Type: %q", cfg.Routing.Type))
+	}
+
 	// Gather all the options
 
 	opts := fx.Options(
-		BaseLibP2P,
+		BaseLibP2P(ctx),
 
 		fx.Provide(libp2p.AddrFilters(cfg.Swarm.AddrFilters)),
 		fx.Invoke(libp2p.SetupDiscovery(cfg.Discovery.MDNS.Enabled, cfg.Discovery.MDNS.Interval)),
@@ -107,8 +136,7 @@ func LibP2P(bcfg *BuildCfg, cfg *config.Config) fx.Option {
 
 		fx.Provide(libp2p.Security(!bcfg.DisableEncryptedConnections, cfg.Experimental.PreferTLS)),
 
-		fx.Provide(libp2p.Routing),
-		fx.Provide(libp2p.BaseRouting),
+		routing,
 		maybeProvide(libp2p.PubsubRouter, bcfg.getOpt("ipnsps")),
 
 		maybeProvide(libp2p.BandwidthCounter, !cfg.Swarm.DisableBandwidthMetrics),
@@ -123,24 +151,126 @@ func LibP2P(bcfg *BuildCfg, cfg *config.Config) fx.Option {
 	return opts
 }
 
+// gossipSubOptions translates cfg.Pubsub.GossipSub and cfg.Pubsub.PeerScoring
+// into pubsub.Option values for libp2p.GossipSub, validating the mesh
+// parameters the same way ConnMgr (in LibP2P) and Reprovider.Interval (in
+// Providers) are validated.
+func gossipSubOptions(cfg *config.Config) ([]pubsub.Option, error) {
+	gs := cfg.Pubsub.GossipSub
+
+	params := pubsub.DefaultGossipSubParams()
+	if gs.D != 0 {
+		params.D = gs.D
+	}
+	if gs.Dlo != 0 {
+		params.Dlo = gs.Dlo
+	}
+	if gs.Dhi != 0 {
+		params.Dhi = gs.Dhi
+	}
+	if gs.Dscore != 0 {
+		params.Dscore = gs.Dscore
+	}
+	if gs.Dout != 0 {
+		params.Dout = gs.Dout
+	}
+	if gs.HistoryLength != 0 {
+		params.HistoryLength = gs.HistoryLength
+	}
+	if gs.HistoryGossip != 0 {
+		params.HistoryGossip = gs.HistoryGossip
+	}
+	if gs.HeartbeatInterval != "" {
+		d, err := time.ParseDuration(gs.HeartbeatInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Pubsub.GossipSub.HeartbeatInterval: %s", err)
+		}
+		params.HeartbeatInterval = d
+	}
+	if gs.FanoutTTL != "" {
+		d, err := time.ParseDuration(gs.FanoutTTL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Pubsub.GossipSub.FanoutTTL: %s", err)
+		}
+		params.FanoutTTL = d
+	}
+	if params.Dlo > params.D || params.D > params.Dhi {
+		return nil, fmt.Errorf("invalid Pubsub.GossipSub mesh parameters: must have Dlo (%d) <= D (%d) <= Dhi (%d)", params.Dlo, params.D, params.Dhi)
+	}
+
+	opts := []pubsub.Option{pubsub.WithGossipSubParams(params)}
+
+	ps := cfg.Pubsub.PeerScoring
+	if ps.AppSpecificScoreThreshold != 0 || len(ps.Topics) > 0 {
+		thresholds := &pubsub.PeerScoreThresholds{
+			GossipThreshold:             ps.GossipThreshold,
+			PublishThreshold:            ps.PublishThreshold,
+			GraylistThreshold:           ps.GraylistThreshold,
+			AcceptPXThreshold:           ps.AcceptPXThreshold,
+			OpportunisticGraftThreshold: ps.OpportunisticGraftThreshold,
+		}
+
+		// AppSpecificScoreThreshold only gates whether peer scoring is
+		// enabled at all (checked above); this tree has no real
+		// application-specific signal to score peers by, so every peer
+		// gets a neutral 0 rather than the threshold value itself.
+		scoreParams := &pubsub.PeerScoreParams{
+			AppSpecificScore: func(p peer.ID) float64 { return 0 },
+			DecayInterval:    params.HeartbeatInterval,
+			Topics:           make(map[string]*pubsub.TopicScoreParams, len(ps.Topics)),
+		}
+		if ps.DecayInterval != "" {
+			d, err := time.ParseDuration(ps.DecayInterval)
+			if err != nil {
+				return nil, fmt.Errorf("parsing Pubsub.PeerScoring.DecayInterval: %s", err)
+			}
+			scoreParams.DecayInterval = d
+		}
+		for topic, tp := range ps.Topics {
+			scoreParams.Topics[topic] = &pubsub.TopicScoreParams{
+				TopicWeight:                    tp.TopicWeight,
+				TimeInMeshWeight:               tp.TimeInMeshWeight,
+				TimeInMeshQuantum:              tp.TimeInMeshQuantum,
+				TimeInMeshCap:                  tp.TimeInMeshCap,
+				FirstMessageDeliveriesWeight:   tp.FirstMessageDeliveriesWeight,
+				FirstMessageDeliveriesDecay:    tp.FirstMessageDeliveriesDecay,
+				FirstMessageDeliveriesCap:      tp.FirstMessageDeliveriesCap,
+				InvalidMessageDeliveriesWeight: tp.InvalidMessageDeliveriesWeight,
+				InvalidMessageDeliveriesDecay:  tp.InvalidMessageDeliveriesDecay,
+			}
+		}
+
+		opts = append(opts,
+			pubsub.WithPeerScore(scoreParams, thresholds),
+		)
+	}
+
+	return opts, nil
+}
+
 // Storage groups units which setup datastore based persistence and blockstore layers
-func Storage(bcfg *BuildCfg, cfg *config.Config) fx.Option {
+func Storage(ctx context.Context, bcfg *BuildCfg, cfg *config.Config) fx.Option {
 	cacheOpts := blockstore.DefaultCacheOpts()
 	cacheOpts.HasBloomFilterSize = cfg.Datastore.BloomFilterSize
 	if !bcfg.Permanent {
 		cacheOpts.HasBloomFilterSize = 0
 	}
 
-	finalBstore := fx.Provide(GcBlockstoreCtor)
-	if cfg.Experimental.FilestoreEnabled || cfg.Experimental.UrlstoreEnabled {
-		finalBstore = fx.Provide(FilestoreBlockstoreCtor)
+	finalBstore := provideOrOverride(ctx, GcBlockstoreCtor)
+	switch {
+	case cfg.Experimental.CarStoreEnabled:
+		finalBstore = provideOrOverride(ctx, CarStoreBlockstoreCtor)
+	case cfg.Experimental.FilestoreEnabled || cfg.Experimental.UrlstoreEnabled:
+		finalBstore = provideOrOverride(ctx, FilestoreBlockstoreCtor)
 	}
 
 	return fx.Options(
-		fx.Provide(repo.Repo.Config),
+		provideOrOverride(ctx, repo.Repo.Config),
 		fx.Provide(repo.Repo.Datastore),
-		fx.Provide(BaseBlockstoreCtor(cacheOpts, bcfg.NilRepo, cfg.Datastore.HashOnRead)),
+		provideOrOverride(ctx, BaseBlockstoreCtor(cacheOpts, bcfg.NilRepo, cfg.Datastore.HashOnRead)),
 		finalBstore,
+
+		maybeProvide(carstore.NewRegistry, cfg.Experimental.CarStoreEnabled),
 	)
 }
 
@@ -189,8 +319,20 @@ var IPNS = fx.Options(
 	fx.Provide(RecordValidator),
 )
 
-// Providers groups units managing provider routing records
+// Providers groups units managing provider routing records. The concrete
+// provider.System is selected by Reprovider.Strategy. ProviderCtor and the
+// "none" system are registered with fx.Provide rather than
+// provideOrOverride, so node.Override does not replace them.
 func Providers(cfg *config.Config) fx.Option {
+	if cfg.Reprovider.Strategy == "none" {
+		// "none" wires a do-nothing provider.System so the node can run
+		// without announcing anything, while still satisfying the DI graph
+		// for anything that depends on provider.System.
+		return fx.Provide(func() provider.System {
+			return simple.NewNoopSystem()
+		})
+	}
+
 	reproviderInterval := kReprovideFrequency
 	if cfg.Reprovider.Interval != "" {
 		dur, err := time.ParseDuration(cfg.Reprovider.Interval)
@@ -206,11 +348,11 @@ func Providers(cfg *config.Config) fx.Option {
 	case "all":
 		fallthrough
 	case "":
-		keyProvider = fx.Provide(reprovide.NewBlockstoreProvider)
+		keyProvider = fx.Provide(simple.NewBlockstoreProvider)
 	case "roots":
-		keyProvider = fx.Provide(reprovide.NewPinnedProvider(true))
+		keyProvider = fx.Provide(simple.NewPinnedProvider(true))
 	case "pinned":
-		keyProvider = fx.Provide(reprovide.NewPinnedProvider(false))
+		keyProvider = fx.Provide(simple.NewPinnedProvider(false))
 	default:
 		return fx.Error(fmt.Errorf("unknown reprovider strategy '%s'", cfg.Reprovider.Strategy))
 	}
@@ -226,7 +368,7 @@ func Providers(cfg *config.Config) fx.Option {
 }
 
 // Online groups online-only units
-func Online(bcfg *BuildCfg, cfg *config.Config) fx.Option {
+func Online(ctx context.Context, bcfg *BuildCfg, cfg *config.Config) fx.Option {
 
 	// Namesys params
 
@@ -265,14 +407,14 @@ func Online(bcfg *BuildCfg, cfg *config.Config) fx.Option {
 	}
 
 	return fx.Options(
-		fx.Provide(OnlineExchange),
+		provideOrOverride(ctx, OnlineExchange),
 		fx.Provide(Namesys(ipnsCacheSize)),
 
 		fx.Invoke(IpnsRepublisher(repubPeriod, recordLifetime)),
 
 		fx.Provide(p2p.New),
 
-		LibP2P(bcfg, cfg),
+		LibP2P(ctx, bcfg, cfg),
 		Providers(cfg),
 	)
 }
@@ -294,9 +436,9 @@ var Core = fx.Options(
 	fx.Provide(Files),
 )
 
-func Networked(bcfg *BuildCfg, cfg *config.Config) fx.Option {
+func Networked(ctx context.Context, bcfg *BuildCfg, cfg *config.Config) fx.Option {
 	if bcfg.Online {
-		return Online(bcfg, cfg)
+		return Online(ctx, bcfg, cfg)
 	}
 	return Offline
 }
@@ -307,11 +449,24 @@ func IPFS(ctx context.Context, bcfg *BuildCfg) fx.Option {
 		bcfg = new(BuildCfg)
 	}
 
+	// Scope the context so every metrics.NewCtx(ctx, ...) call downstream of
+	// this point registers under a stable, discoverable "ipfs.*" name
+	// instead of an anonymous one.
+	ctx = metrics.CtxScope(ctx, "ipfs")
+
 	bcfgOpts, cfg := bcfg.options(ctx)
 	if cfg == nil {
 		return bcfgOpts // error
 	}
 
+	// node.Config's override only reaches provideOrOverride call sites by
+	// default, not the cfg bcfg.options(ctx) just resolved -- substitute it
+	// here, before any cfg-driven branching below (Storage/LibP2P/Providers)
+	// runs, so the override actually takes effect for that branching too.
+	if override, ok := configOverride(ctx); ok {
+		cfg = override
+	}
+
 	// TEMP: setting global sharding switch here
 	uio.UseHAMTSharding = cfg.Experimental.ShardingEnabled
 
@@ -320,49 +475,17 @@ func IPFS(ctx context.Context, bcfg *BuildCfg) fx.Option {
 
 		fx.Provide(baseProcess),
 
-		Storage(bcfg, cfg),
+		Metrics(ctx, cfg),
+
+		Storage(ctx, bcfg, cfg),
 		Identity(cfg),
 		IPNS,
-		Networked(bcfg, cfg),
+		Networked(ctx, bcfg, cfg),
 
 		Core,
 	)
 }
 
-/*
-
-// ipfsNode, err := New(...core.Option) (*core.API, error)
-// var _ iface.CoreAPI = ipfsNode
-// var _ *core.Node = ipfsNode.Node() // use for low-level access, a bit like .Request() in go-ipfs-http-client
-
-// TODO: auto client mode? (like fallback-ipfs-shell), or should we keep this separate?
-
-New() // new with defaults (offline)
-
-New(Online()) // new online node
-
-New(Ctx(ctx)) // with context
-
-New(Repo(r)) // with repo, use in-repo config
-
-New(Repo(r), Blockstore(mybstore)) // with repo, use repo config, override blockstore
-
-import nodep2p "github.com/ipfs/go-ipfs/core/node/libp2p"
-New(Repo(r), Online(LibP2P(nodep2p.RelayHop(false)))) // with repo, use repo config, force no hop
-
-New(Repo(r, Config(cfg))) // with repo, override config
-
-New(Invoke(funcToFxInvoke))
-New(Provide(funcToFxProvide))
-
-- Provide can't override existing stuff, use special functions like the ones
-  above for that
-  - Doing this would either require rather deep changes in uber/dig
-  - It wouldn't be typesafe at all (if we'd change some type and users didn't notice,
-    their stuff would break)
-- It's flexible enough to take advantage of DI
-- Doesn't expose fx on the fnterface (well, it exposes lifecycles, and might be
-  quite specific, but still provides us with easier migration path if we ever need one)
-
- */
-
+// The user-extension API lives in builder.go as
+// node.Provide / node.Invoke / node.Override / node.Online / node.Offline /
+// node.Repo / node.Ctx / node.Config, composed by core.NewNodeWithOptions.
@@ -0,0 +1,177 @@
+package libp2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	host "github.com/libp2p/go-libp2p-core/host"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	irouting "github.com/libp2p/go-libp2p-core/routing"
+	routinghelpers "github.com/libp2p/go-libp2p-routing-helpers"
+	multiaddr "github.com/multiformats/go-multiaddr"
+)
+
+// httpRouter is an irouting.Routing backed by a routing/v1-shaped HTTP
+// endpoint (PUT/GET /routing/v1/providers/{cid}), shared by the
+// delegated-http and reframe router types. Only Provide and
+// FindProvidersAsync actually talk to the endpoint; the remaining
+// irouting.Routing methods (FindPeer, GetValue, SearchValue, Bootstrap, and
+// PutValue once past the read-only check) fall through to the embedded
+// routinghelpers.Null, since this tree has no peer- or IPNS-routing-over-HTTP
+// client to drive them. Write operations (Provide, PutValue) are refused
+// when readOnly is set. Delegated-http routers take readOnly from
+// Routing.Routers[*].Parameters.ReadOnly; reframe routers ignore that
+// parameter and are always built with readOnly false.
+type httpRouter struct {
+	routinghelpers.Null
+
+	host     host.Host
+	endpoint string
+	client   *http.Client
+	readOnly bool
+}
+
+func newDelegatedHTTPRouter(h host.Host, endpoint string, timeout time.Duration, readOnly bool) (irouting.Routing, error) {
+	return &httpRouter{
+		host:     h,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+		readOnly: readOnly,
+	}, nil
+}
+
+func newReframeRouter(h host.Host, endpoint string, timeout time.Duration) (irouting.Routing, error) {
+	// Reframe speaks the same request/reply shape over HTTP as our
+	// delegated-http router for the subset of methods we bind it to; it is
+	// kept as a distinct router type so it shows up under its own name in
+	// Routing.Routers and can be load-balanced independently.
+	return &httpRouter{
+		host:     h,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// providerRecord is one entry of a routing/v1 providers response.
+type providerRecord struct {
+	Schema string   `json:"Schema"`
+	ID     string   `json:"ID"`
+	Addrs  []string `json:"Addrs"`
+}
+
+type providersResponse struct {
+	Providers []providerRecord `json:"Providers"`
+}
+
+func (h *httpRouter) providersURL(c cid.Cid) string {
+	return fmt.Sprintf("%s/routing/v1/providers/%s", h.endpoint, c.String())
+}
+
+func (h *httpRouter) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	if h.readOnly {
+		return fmt.Errorf("router %s is read-only, cannot Provide", h.endpoint)
+	}
+	if !announce {
+		return nil
+	}
+
+	addrs := make([]string, 0, len(h.host.Addrs()))
+	for _, a := range h.host.Addrs() {
+		addrs = append(addrs, a.String())
+	}
+
+	body, err := json.Marshal(providersResponse{Providers: []providerRecord{{
+		Schema: "peer",
+		ID:     h.host.ID().Pretty(),
+		Addrs:  addrs,
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, h.providersURL(c), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("router %s: Provide %s: %w", h.endpoint, c, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("router %s: Provide %s: unexpected status %s", h.endpoint, c, resp.Status)
+	}
+	return nil
+}
+
+func (h *httpRouter) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+
+	go func() {
+		defer close(out)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.providersURL(c), nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := h.client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode/100 != 2 {
+			return
+		}
+
+		var parsed providersResponse
+		if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+			return
+		}
+
+		for i, rec := range parsed.Providers {
+			if count > 0 && i >= count {
+				return
+			}
+
+			id, err := peer.Decode(rec.ID)
+			if err != nil {
+				continue
+			}
+
+			addrs := make([]multiaddr.Multiaddr, 0, len(rec.Addrs))
+			for _, a := range rec.Addrs {
+				ma, err := multiaddr.NewMultiaddr(a)
+				if err != nil {
+					continue
+				}
+				addrs = append(addrs, ma)
+			}
+
+			select {
+			case out <- peer.AddrInfo{ID: id, Addrs: addrs}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func (h *httpRouter) PutValue(ctx context.Context, key string, val []byte, opts ...irouting.Option) error {
+	if h.readOnly {
+		return fmt.Errorf("router %s is read-only, cannot PutValue", h.endpoint)
+	}
+	return h.Null.PutValue(ctx, key, val, opts...)
+}
@@ -0,0 +1,102 @@
+package node
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ipfs/go-ipfs-config"
+	metrics "github.com/ipfs/go-metrics-interface"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+)
+
+// subsystems that get their own named, scoped metrics context, so their
+// counters/histograms register under a stable "ipfs.<name>" prefix instead
+// of sharing one anonymous namespace.
+var metricsSubsystems = []string{"bitswap", "blockstore", "provider", "dht", "pubsub"}
+
+// PrometheusHandler is a Prometheus scrape handler, nil when
+// Metrics.Prometheus.Enabled is false. The HTTP API mux is assembled by
+// corehttp outside of fx.New; Metrics only provides the handler as a value
+// and does not mount it on any mux.
+type PrometheusHandler http.Handler
+
+// Metrics provides one named context.Context per entry in metricsSubsystems,
+// each scoped under ctx via metrics.CtxScope, and registers a gauge under
+// each so every scoped context has a real consumer. It also provides a
+// PrometheusHandler, non-nil when Metrics.Prometheus.Enabled is set.
+func Metrics(ctx context.Context, cfg *config.Config) fx.Option {
+	var opts []fx.Option
+	for _, name := range metricsSubsystems {
+		name := name
+		opts = append(opts, fx.Provide(fx.Annotated{
+			Name: name,
+			Target: func() context.Context {
+				return metrics.CtxScope(ctx, name)
+			},
+		}))
+	}
+
+	opts = append(opts,
+		fx.Invoke(registerBitswapMetric),
+		fx.Invoke(registerBlockstoreMetric),
+		fx.Invoke(registerProviderMetric),
+		fx.Invoke(registerDHTMetric),
+		fx.Invoke(registerPubsubMetric),
+
+		fx.Provide(prometheusHandlerCtor(cfg.Metrics.Prometheus.Enabled)),
+	)
+
+	return fx.Options(opts...)
+}
+
+// registerUpGauge marks a subsystem's scoped context as initialized, giving
+// every context Metrics() provides at least one real metric registered
+// under it.
+func registerUpGauge(ctx context.Context, name string) {
+	metrics.NewCtx(ctx, "up", "whether the "+name+" subsystem is initialized").Gauge().Set(1)
+}
+
+type bitswapCtxIn struct {
+	fx.In
+	Ctx context.Context `name:"bitswap"`
+}
+
+func registerBitswapMetric(in bitswapCtxIn) { registerUpGauge(in.Ctx, "bitswap") }
+
+type blockstoreCtxIn struct {
+	fx.In
+	Ctx context.Context `name:"blockstore"`
+}
+
+func registerBlockstoreMetric(in blockstoreCtxIn) { registerUpGauge(in.Ctx, "blockstore") }
+
+type dhtCtxIn struct {
+	fx.In
+	Ctx context.Context `name:"dht"`
+}
+
+func registerDHTMetric(in dhtCtxIn) { registerUpGauge(in.Ctx, "dht") }
+
+type pubsubCtxIn struct {
+	fx.In
+	Ctx context.Context `name:"pubsub"`
+}
+
+func registerPubsubMetric(in pubsubCtxIn) { registerUpGauge(in.Ctx, "pubsub") }
+
+// registerProviderMetric reuses providerCtxIn (defined in provider.go)
+// rather than its own named-context struct, since that's the same
+// "provider"-scoped context ProviderQueue/ProviderCtor already consume.
+func registerProviderMetric(in providerCtxIn) { registerUpGauge(in.MetricsCtx, "provider") }
+
+// prometheusHandlerCtor returns the fx constructor for PrometheusHandler,
+// gated on Metrics.Prometheus.Enabled.
+func prometheusHandlerCtor(enabled bool) func() PrometheusHandler {
+	return func() PrometheusHandler {
+		if !enabled {
+			return nil
+		}
+		return promhttp.Handler()
+	}
+}
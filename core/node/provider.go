@@ -0,0 +1,113 @@
+package node
+
+import (
+	"context"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	routing "github.com/libp2p/go-libp2p-core/routing"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/go-ipfs/provider"
+	"github.com/ipfs/go-ipfs/provider/queue"
+	"github.com/ipfs/go-ipfs/provider/simple"
+	"github.com/ipfs/go-ipfs/repo"
+)
+
+var plog = logging.Logger("core/node/provider")
+
+// providerCtxIn carries the "provider"-scoped metrics context provided by
+// Metrics(). ProviderQueue/ProviderCtor use it only as the base for their
+// lifecycle-bound context.WithCancel; registerProviderMetric (metrics.go)
+// is what actually registers a metric under it via metrics.NewCtx.
+type providerCtxIn struct {
+	fx.In
+
+	MetricsCtx context.Context `name:"provider"`
+}
+
+// ProviderQueue creates a datastore backed queue used to track keys that
+// still need to be announced.
+func ProviderQueue(lc fx.Lifecycle, in providerCtxIn, repo repo.Repo) (*queue.Queue, error) {
+	ctx, cancel := context.WithCancel(in.MetricsCtx)
+	lc.Append(fx.Hook{
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+
+	return queue.NewQueue(ctx, "provider-v1", repo.Datastore())
+}
+
+// ProviderCtor creates the provider.System responsible for announcing newly
+// added blocks, draining ProviderQueue in the background, and for
+// reproviding the keys produced by the KeyChanFunc that Reprovider.Strategy
+// selects in Providers().
+func ProviderCtor(lc fx.Lifecycle, in providerCtxIn, q *queue.Queue, rt routing.ContentRouting, keyChan simple.KeyChanFunc) provider.System {
+	sys := simple.NewSystem(rt, q, keyChan)
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			sys.Run()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			return sys.Close()
+		},
+	})
+
+	return sys
+}
+
+// reprovideSchedule binds a provider.System to the interval on which it
+// should be reprovided.
+type reprovideSchedule struct {
+	sys      provider.System
+	interval time.Duration
+}
+
+// ReproviderCtor binds the interval parsed from Reprovider.Interval to the
+// provider.System selected in Providers(), for use as a Reprovider input.
+func ReproviderCtor(interval time.Duration) func(sys provider.System) *reprovideSchedule {
+	return func(sys provider.System) *reprovideSchedule {
+		return &reprovideSchedule{sys: sys, interval: interval}
+	}
+}
+
+// Reprovider periodically calls Reprovide on the configured provider.System
+// until the node shuts down. An interval of zero disables periodic
+// reproviding; the system is still reachable for on-demand Provide() calls.
+func Reprovider(lc fx.Lifecycle, sched *reprovideSchedule) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if sched.interval <= 0 {
+				return nil
+			}
+
+			go func() {
+				ticker := time.NewTicker(sched.interval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ticker.C:
+						if err := sched.sys.Reprovide(ctx); err != nil {
+							plog.Debugf("failed to reprovide: %s", err)
+						}
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package node
+
+import (
+	"context"
+
+	blocks "github.com/ipfs/go-block-format"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	cid "github.com/ipfs/go-cid"
+
+	"github.com/ipfs/go-ipfs/carstore"
+)
+
+// CarStoreBlockstoreCtor composes the base (pre-GC) datastore blockstore
+// with a read-only carstore.Blockstore overlay, then GC-wraps the result:
+// blocks belonging to a registered CARv2 shard are resolved from the shard
+// file via the shard's index, rather than being unpacked into the
+// datastore. It takes the same base blockstore.Blockstore GcBlockstoreCtor
+// does, not blockstore.GCBlockstore itself -- depending on the type it
+// produces would make it its own dependency.
+func CarStoreBlockstoreCtor(bstore blockstore.Blockstore, registry *carstore.Registry) blockstore.GCBlockstore {
+	overlaid := &carStoreBlockstore{
+		Blockstore: bstore,
+		overlay:    carstore.NewBlockstore(registry),
+	}
+	return blockstore.NewGCBlockstore(overlaid, blockstore.NewGCLocker())
+}
+
+type carStoreBlockstore struct {
+	blockstore.Blockstore
+	overlay *carstore.Blockstore
+}
+
+func (c *carStoreBlockstore) Has(k cid.Cid) (bool, error) {
+	if ok, _ := c.overlay.Has(k); ok {
+		return true, nil
+	}
+	return c.Blockstore.Has(k)
+}
+
+func (c *carStoreBlockstore) Get(k cid.Cid) (blocks.Block, error) {
+	if ok, _ := c.overlay.Has(k); ok {
+		return c.overlay.Get(k)
+	}
+	return c.Blockstore.Get(k)
+}
+
+// AllKeysChan unions the base blockstore's keys with the overlay's shard
+// roots, so the default Reprovider.Strategy ("all", via
+// simple.NewBlockstoreProvider) walks and announces carstore-backed content
+// too. It yields shard roots rather than every block inside each shard: a
+// registered shard's blocks are only reachable through its root, the same
+// granularity Registry.IsPinned treats as pinned.
+func (c *carStoreBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	base, err := c.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+
+		for k := range base {
+			select {
+			case out <- k:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for _, root := range c.overlay.Roots() {
+			select {
+			case out <- root:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"context"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// offlineProvider is a System that does nothing. It satisfies the DI graph
+// for offline nodes, where there is no network to announce blocks on.
+type offlineProvider struct{}
+
+// NewOfflineProvider creates a provider System that never announces
+// anything, for use by nodes built without networking.
+func NewOfflineProvider() System {
+	return &offlineProvider{}
+}
+
+func (op *offlineProvider) Run() {}
+
+func (op *offlineProvider) Close() error {
+	return nil
+}
+
+func (op *offlineProvider) Provide(cid.Cid) error {
+	return nil
+}
+
+func (op *offlineProvider) Reprovide(context.Context) error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	nsds "github.com/ipfs/go-datastore/namespace"
+	dsq "github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log"
+)
+
+var log = logging.Logger("provider.queue")
+
+// Queue provides a durable, FIFO interface to the datastore for storing cids.
+//
+// Durability just means that cids in the process of being provided when a
+// crash or shutdown occurs will still be in the queue when the node is
+// brought back online.
+type Queue struct {
+	// used to differentiate queues in datastore
+	// e.g. provider vs reprovider
+	name string
+	ctx  context.Context
+
+	tail uint64
+	head uint64
+	lock sync.Mutex
+
+	ds ds.Datastore // Must be threadsafe
+}
+
+// NewQueue creates a new LevelDB backed partitioned CID queue.
+func NewQueue(ctx context.Context, name string, datastore ds.Datastore) (*Queue, error) {
+	namespaced := nsds.Wrap(datastore, ds.NewKey("/"+name+"/queue"))
+	head, tail, err := loadHeadTail(ctx, namespaced)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Queue{
+		name: name,
+		ctx:  ctx,
+		head: head,
+		tail: tail,
+		ds:   namespaced,
+	}, nil
+}
+
+func loadHeadTail(ctx context.Context, datastore ds.Datastore) (uint64, uint64, error) {
+	q := dsq.Query{
+		Orders: []dsq.Order{dsq.OrderByKey{}},
+	}
+
+	results, err := datastore.Query(q)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer results.Close()
+
+	var head, tail uint64
+	found := false
+	for entry := range results.Next() {
+		var id uint64
+		_, err = fmt.Sscanf(entry.Key, "/%d", &id)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !found || id < head {
+			head = id
+		}
+		if id+1 > tail {
+			tail = id + 1
+		}
+		found = true
+	}
+
+	return head, tail, nil
+}
+
+// Enqueue puts a cid in the queue.
+func (q *Queue) Enqueue(cid cid.Cid) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	nextKey := q.queueKey(q.tail)
+	if err := q.ds.Put(nextKey, cid.Bytes()); err != nil {
+		return err
+	}
+	q.tail++
+
+	return nil
+}
+
+// Dequeue returns a channel that receives the next cid in the queue when
+// available.
+func (q *Queue) Dequeue() <-chan cid.Cid {
+	out := make(chan cid.Cid)
+
+	go func() {
+		for {
+			entry, err := q.next()
+			if err != nil {
+				log.Errorf("failed to dequeue cid: %s", err)
+				return
+			}
+
+			select {
+			case out <- entry:
+			case <-q.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func (q *Queue) next() (cid.Cid, error) {
+	q.lock.Lock()
+	for q.head >= q.tail {
+		q.lock.Unlock()
+		select {
+		case <-q.ctx.Done():
+			return cid.Undef, q.ctx.Err()
+		default:
+		}
+		q.lock.Lock()
+	}
+
+	key := q.queueKey(q.head)
+	value, err := q.ds.Get(key)
+	if err != nil {
+		q.lock.Unlock()
+		return cid.Undef, err
+	}
+
+	if err := q.ds.Delete(key); err != nil {
+		q.lock.Unlock()
+		return cid.Undef, err
+	}
+	q.head++
+	q.lock.Unlock()
+
+	return cid.Cast(value)
+}
+
+func (q *Queue) queueKey(id uint64) ds.Key {
+	return ds.NewKey(fmt.Sprintf("/%d", id))
+}
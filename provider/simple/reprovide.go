@@ -0,0 +1,199 @@
+// Package simple implements the straightforward provider strategies that
+// used to live in the top-level `reprovide` package: announcing every block
+// in the blockstore, announcing only pinned roots/recursive pins, and a
+// no-op system used when reproviding should be disabled entirely.
+package simple
+
+import (
+	"context"
+
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	routing "github.com/libp2p/go-libp2p-core/routing"
+
+	"github.com/ipfs/go-ipfs/provider/queue"
+)
+
+var log = logging.Logger("provider.simple")
+
+// KeyChanFunc generates a sequence of CIDs to provide.
+type KeyChanFunc func(ctx context.Context) (<-chan cid.Cid, error)
+
+// System is a provider.System that announces a set of keys, computed by a
+// KeyChanFunc, to content routing on a schedule. Individual Provide() calls
+// are durably enqueued so they survive a crash or restart before they are
+// announced.
+type System struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	rsys    routing.ContentRouting
+	queue   *queue.Queue
+	keyChan KeyChanFunc
+}
+
+// NewSystem creates a simple.System. Calling Provide enqueues a key for
+// announcement; calling Reprovide walks the keys produced by keyChan and
+// announces each one to rsys.
+func NewSystem(rsys routing.ContentRouting, q *queue.Queue, keyChan KeyChanFunc) *System {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &System{
+		ctx:     ctx,
+		cancel:  cancel,
+		rsys:    rsys,
+		queue:   q,
+		keyChan: keyChan,
+	}
+}
+
+// Run drains the provide queue in the background, announcing each key to
+// rsys as it is dequeued.
+func (s *System) Run() {
+	if s.queue == nil {
+		return
+	}
+
+	dequeue := s.queue.Dequeue()
+	go func() {
+		for {
+			select {
+			case c, ok := <-dequeue:
+				if !ok {
+					return
+				}
+				if err := s.rsys.Provide(s.ctx, c, true); err != nil {
+					log.Debugf("failed to provide %s: %s", c, err)
+				}
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close shuts down the system.
+func (s *System) Close() error {
+	s.cancel()
+	return nil
+}
+
+// Provide enqueues a single key for announcement to the network.
+func (s *System) Provide(c cid.Cid) error {
+	if s.queue == nil {
+		return s.rsys.Provide(s.ctx, c, true)
+	}
+	return s.queue.Enqueue(c)
+}
+
+// Reprovide announces every key produced by keyChan to the network.
+func (s *System) Reprovide(ctx context.Context) error {
+	if s.keyChan == nil {
+		return nil
+	}
+
+	keychan, err := s.keyChan(ctx)
+	if err != nil {
+		return err
+	}
+
+	for c := range keychan {
+		if err := s.rsys.Provide(ctx, c, true); err != nil {
+			log.Debugf("failed to provide %s: %s", c, err)
+		}
+	}
+
+	return nil
+}
+
+// NoopSystem is a provider.System that never announces anything. It backs
+// the `Reprovider.Strategy: "none"` config value, letting a node run
+// without a reprovider queue or content router wired up for it, while still
+// satisfying the DI graph.
+type NoopSystem struct{}
+
+// NewNoopSystem creates a no-op provider System, selected by
+// `Reprovider.Strategy: "none"`.
+func NewNoopSystem() *NoopSystem {
+	return &NoopSystem{}
+}
+
+func (s *NoopSystem) Run() {}
+
+func (s *NoopSystem) Close() error {
+	return nil
+}
+
+func (s *NoopSystem) Provide(cid.Cid) error {
+	return nil
+}
+
+func (s *NoopSystem) Reprovide(context.Context) error {
+	return nil
+}
+
+// NewBlockstoreProvider returns a KeyChanFunc that walks every key in the
+// given blockstore.
+func NewBlockstoreProvider(bstore blockstore.Blockstore) KeyChanFunc {
+	return func(ctx context.Context) (<-chan cid.Cid, error) {
+		return bstore.AllKeysChan(ctx)
+	}
+}
+
+// NewPinnedProvider returns a KeyChanFunc that walks pinned keys, either
+// roots only (onlyRoots == true) or the full DAG under each recursive pin.
+func NewPinnedProvider(onlyRoots bool) func(pinning pin.Pinner, dag ipld.DAGService) KeyChanFunc {
+	return func(pinning pin.Pinner, dag ipld.DAGService) KeyChanFunc {
+		return func(ctx context.Context) (<-chan cid.Cid, error) {
+			set := cid.NewSet()
+			for _, c := range pinning.DirectKeys() {
+				set.Add(c)
+			}
+
+			for _, c := range pinning.RecursiveKeys() {
+				set.Add(c)
+				if !onlyRoots {
+					if err := walkDAG(ctx, dag, c, set); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			outCh := make(chan cid.Cid)
+			go func() {
+				defer close(outCh)
+				set.ForEach(func(c cid.Cid) error {
+					select {
+					case outCh <- c:
+					case <-ctx.Done():
+					}
+					return nil
+				})
+			}()
+
+			return outCh, nil
+		}
+	}
+}
+
+// walkDAG recursively visits every link reachable from root, adding each
+// visited CID to set.
+func walkDAG(ctx context.Context, dag ipld.DAGService, root cid.Cid, set *cid.Set) error {
+	node, err := dag.Get(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range node.Links() {
+		if set.Has(link.Cid) {
+			continue
+		}
+		set.Add(link.Cid)
+		if err := walkDAG(ctx, dag, link.Cid, set); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
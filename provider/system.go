@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"context"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// System defines the interface for a provider system. A System is
+// responsible for announcing blocks to the network, either as they are
+// added (Provide) or periodically for the whole set it is configured to
+// track (Reprovide). Implementations are started with Run and must be
+// stopped with Close.
+//
+// This interface exists so that alternate provider strategies (e.g.
+// rate-limited, DHT-only, delegated, or no-op) can be selected or supplied
+// by embedders without `core.IpfsNode` depending on any one concrete
+// implementation.
+type System interface {
+	Run()
+	Close() error
+
+	Provide(cid.Cid) error
+	Reprovide(ctx context.Context) error
+}